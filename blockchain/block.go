@@ -3,6 +3,7 @@ package blockchain
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/gob"
 	"time"
 )
@@ -17,20 +18,26 @@ type Block struct {
 	Hash          []byte         // SHA-256 digest serving as this block's unique identifier
 	Validator     []byte         // Public key of the validator node that proposed this block
 	Nonce         int            // Proof-of-work solution or consensus-specific challenge value
+	Slot          uint64         // PoS slot this block was proposed for
+	VRFProof      []byte         // Validator's VRF proof for Slot, verifiable by any node against the validator set snapshot
 }
 
 // NewBlock constructs and initializes a new Block instance with validated transactions.
 // The block is cryptographically linked to its predecessor via prevBlockHash, establishing
 // an immutable chain. The validator parameter identifies the consensus participant responsible
-// for block proposal, enabling accountability in the network.
+// for block proposal, enabling accountability in the network. slot and vrfProof are the PoS
+// slot the block was proposed for and the validator's corresponding VRF proof (see
+// ProposeBlock), so any node can independently verify the validator's eligibility.
 //
 // Returns a pointer to the newly created Block with its hash computed.
-func NewBlock(transactions []*Transaction, prevBlockHash []byte, validator []byte) *Block {
+func NewBlock(transactions []*Transaction, prevBlockHash []byte, validator []byte, slot uint64, vrfProof []byte) *Block {
 	block := &Block{
 		Timestamp:     time.Now(),
 		Transactions:  transactions,
 		PrevBlockHash: prevBlockHash,
 		Validator:     validator,
+		Slot:          slot,
+		VRFProof:      vrfProof,
 	}
 	// Compute deterministic hash immediately to maintain referential integrity
 	block.Hash = block.calculateHash()
@@ -38,65 +45,162 @@ func NewBlock(transactions []*Transaction, prevBlockHash []byte, validator []byt
 }
 
 // calculateHash generates a SHA-256 digest of the block's canonical representation.
-// This cryptographic commitment includes all transactions (via their hashes), the previous
-// block hash, and temporal data. The resulting hash serves as both a unique identifier
-// and tamper-evident seal, as any modification would produce a different hash value.
+// This cryptographic commitment includes the Merkle root of all transactions, the previous
+// block hash, temporal data, and the proposer's slot and VRF proof. The resulting hash serves
+// as both a unique identifier and tamper-evident seal, as any modification would produce a
+// different hash value.
 //
 // Implementation uses SHA-256 for its collision resistance and preimage security properties.
 func (b *Block) calculateHash() []byte {
-	// Aggregate transaction hashes to create a compact cryptographic commitment
-	var txHashes []byte
-	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.Hash()...)
-	}
+	slotBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(slotBytes, b.Slot)
 
 	// Concatenate all block components for deterministic hashing
 	hash := sha256.Sum256(bytes.Join([][]byte{
 		b.PrevBlockHash,
-		txHashes,
+		b.MerkleRoot(),
 		[]byte(b.Timestamp.String()),
+		slotBytes,
+		b.VRFProof,
 	}, []byte{}))
 
 	return hash[:]
 }
 
-// Serialize encodes the Block into a byte slice using gob encoding.
-// This enables efficient persistence to disk or transmission over the network
-// while preserving the complete block structure including all nested data.
-//
-// Panics if encoding fails, as this indicates a critical system error rather
-// than a recoverable condition (e.g., corrupted memory).
+// Serialize encodes the Block into a byte slice for persistence or network
+// transmission, using the current canonical format (versionLengthPrefixed).
+// Every payload starts with a uint16 version prefix; DeserializeBlock reads
+// that prefix and dispatches to the matching decoder, so a schema change
+// (a new field) introduces a new version rather than invalidating blocks
+// already on disk.
 func (b *Block) Serialize() []byte {
-	var result bytes.Buffer
-	encoder := gob.NewEncoder(&result)
+	return b.SerializeV2()
+}
+
+// SerializeV1 encodes the block with encoding/gob, prefixed with its version
+// tag. Kept for reading/writing data from before the length-prefixed format
+// existed; new code should prefer SerializeV2.
+func (b *Block) SerializeV1() []byte {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(b); err != nil {
+		panic(err)
+	}
+
+	var out bytes.Buffer
+	putUint16(&out, versionGob)
+	out.Write(payload.Bytes())
+	return out.Bytes()
+}
+
+// SerializeV2 encodes the block as a uint16 version prefix followed by every
+// field length-tagged in a fixed order. Each transaction is in turn encoded
+// with its own versioned Serialize, so Block and Transaction schemas can
+// evolve independently of one another.
+func (b *Block) SerializeV2() []byte {
+	var buf bytes.Buffer
+	putUint16(&buf, versionLengthPrefixed)
 
-	err := encoder.Encode(b)
+	timestamp, err := b.Timestamp.MarshalBinary()
 	if err != nil {
-		// Serialization failure is non-recoverable; panic to prevent data corruption
 		panic(err)
 	}
+	putBytes(&buf, timestamp)
 
-	return result.Bytes()
+	putUint64(&buf, uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		putBytes(&buf, tx.Serialize())
+	}
+
+	putBytes(&buf, b.PrevBlockHash)
+	putBytes(&buf, b.Hash)
+	putBytes(&buf, b.Validator)
+	putInt64(&buf, int64(b.Nonce))
+	putUint64(&buf, b.Slot)
+	putBytes(&buf, b.VRFProof)
+
+	return buf.Bytes()
 }
 
-// DeserializeBlock reconstructs a Block from its serialized byte representation.
-// This is the inverse operation of Serialize(), used when loading blocks from
-// persistent storage or receiving them from network peers.
-//
-// Parameters:
-//   - data: gob-encoded byte slice representing a serialized Block
-//
-// Returns a pointer to the reconstructed Block instance.
-// Panics if deserialization fails due to malformed data.
-func DeserializeBlock(data []byte) *Block {
+// DeserializeBlock decodes a byte array produced by Serialize back into a
+// Block, dispatching on its version prefix. It returns an error rather than
+// panicking so a caller that received a malformed or corrupted block from a
+// peer can quarantine it instead of crashing the node.
+func DeserializeBlock(data []byte) (*Block, error) {
+	r := bytes.NewReader(data)
+
+	version, err := takeUint16(r)
+	if err != nil {
+		return nil, err
+	}
+
 	var block Block
-	decoder := gob.NewDecoder(bytes.NewReader(data))
+	switch version {
+	case versionGob:
+		if err := gob.NewDecoder(r).Decode(&block); err != nil {
+			return nil, err
+		}
+	case versionLengthPrefixed:
+		if err := decodeBlockV2(r, &block); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownVersion
+	}
+
+	return &block, nil
+}
 
-	err := decoder.Decode(&block)
+func decodeBlockV2(r *bytes.Reader, block *Block) error {
+	timestampBytes, err := takeBytes(r)
 	if err != nil {
-		// Corrupted block data threatens chain integrity; fail fast
-		panic(err)
+		return err
+	}
+	if err := block.Timestamp.UnmarshalBinary(timestampBytes); err != nil {
+		return err
+	}
+
+	// Each transaction entry is at minimum an empty length-prefixed blob (4
+	// bytes), so bound txCount against that before trusting it to size an
+	// allocation.
+	txCount, err := takeCount(r, 4)
+	if err != nil {
+		return err
+	}
+	block.Transactions = make([]*Transaction, txCount)
+	for i := range block.Transactions {
+		txBytes, err := takeBytes(r)
+		if err != nil {
+			return err
+		}
+		tx, err := DeserializeTransaction(txBytes)
+		if err != nil {
+			return err
+		}
+		block.Transactions[i] = tx
+	}
+
+	if block.PrevBlockHash, err = takeBytes(r); err != nil {
+		return err
+	}
+	if block.Hash, err = takeBytes(r); err != nil {
+		return err
+	}
+	if block.Validator, err = takeBytes(r); err != nil {
+		return err
+	}
+
+	nonce, err := takeInt64(r)
+	if err != nil {
+		return err
+	}
+	block.Nonce = int(nonce)
+
+	if block.Slot, err = takeUint64(r); err != nil {
+		return err
+	}
+	if block.VRFProof, err = takeBytes(r); err != nil {
+		return err
 	}
 
-	return &block
+	return nil
 }
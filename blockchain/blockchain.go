@@ -0,0 +1,314 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dbFile       = "blockchain.db"
+	blocksBucket = "blocks"
+	lastHashKey  = "l"
+)
+
+// Blockchain is a persistent, BoltDB-backed chain of blocks. Rather than
+// holding the whole chain in memory, it only tracks the tip; every other
+// block is looked up from the blocks bucket on demand via Iterator.
+type Blockchain struct {
+	Tip []byte
+	DB  *bolt.DB
+
+	// Signer pins the hashing/chain-ID policy every transaction AddBlock
+	// accepts must be verified under (see Transaction.VerifyWithSigner), so
+	// a transaction signed for a different chain is rejected outright
+	// instead of trusting whatever chain ID its own signature claims.
+	Signer Signer
+}
+
+func dbExists() bool {
+	_, err := os.Stat(dbFile)
+	return !os.IsNotExist(err)
+}
+
+// InitBlockchain creates a brand-new chain on disk, seeded with a genesis
+// block whose coinbase transaction pays the block subsidy to address.
+// signer pins the chain ID AddBlock will require every transaction to be
+// verified under (see Blockchain.Signer); a nil signer defaults to
+// LegacySigner{}. It panics if a chain already exists at dbFile.
+func InitBlockchain(address []byte, signer Signer) *Blockchain {
+	if signer == nil {
+		signer = LegacySigner{}
+	}
+	if dbExists() {
+		log.Panic("ERROR: a blockchain already exists, use ContinueBlockchain instead")
+	}
+
+	var tip []byte
+
+	db, err := bolt.Open(dbFile, 0600, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		cbtx, err := NewCoinbaseTx(address, "")
+		if err != nil {
+			return err
+		}
+		genesis := NewBlock([]*Transaction{cbtx}, []byte{}, address, 0, nil)
+
+		b, err := tx.CreateBucket([]byte(blocksBucket))
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put(genesis.Hash, genesis.Serialize()); err != nil {
+			return err
+		}
+		if err := b.Put([]byte(lastHashKey), genesis.Hash); err != nil {
+			return err
+		}
+
+		tip = genesis.Hash
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bc := &Blockchain{Tip: tip, DB: db, Signer: signer}
+
+	UTXOSet := UTXOSet{Blockchain: bc}
+	UTXOSet.Reindex()
+
+	return bc
+}
+
+// ContinueBlockchain opens an existing chain on disk at its current tip.
+// signer pins the chain ID AddBlock will require every transaction to be
+// verified under (see Blockchain.Signer); a nil signer defaults to
+// LegacySigner{}. It panics if no chain exists yet.
+func ContinueBlockchain(signer Signer) *Blockchain {
+	if !dbExists() {
+		log.Panic("ERROR: no existing blockchain found, create one first")
+	}
+	if signer == nil {
+		signer = LegacySigner{}
+	}
+
+	var tip []byte
+
+	db, err := bolt.Open(dbFile, 0600, nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		tip = b.Get([]byte(lastHashKey))
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return &Blockchain{Tip: tip, DB: db, Signer: signer}
+}
+
+// AddBlock appends a new block of transactions onto the chain tip, persists
+// it to the blocks bucket, advances the tip, and updates the UTXO set so
+// balance queries and future transaction construction stay O(unspent)
+// instead of O(chain). slot and vrfProof come from a validator's successful
+// ProposeBlock call. Every non-coinbase transaction must verify against the
+// outputs it references, already present earlier in the chain, under bc's
+// own pinned Signer (see Blockchain.Signer) rather than whatever chain ID
+// its own signature claims, and every input it spends must still be unspent
+// in the UTXO set, or the whole block is rejected before anything is
+// written to disk; this also catches two transactions in the same block
+// spending the same output.
+func (bc *Blockchain) AddBlock(transactions []*Transaction, validator []byte, slot uint64, vrfProof []byte) (*Block, error) {
+	utxoSet := UTXOSet{Blockchain: bc}
+	spentInBlock := make(map[string]bool)
+
+	for _, transaction := range transactions {
+		if transaction.IsCoinbase() {
+			continue
+		}
+
+		prevTXs, err := bc.prevTransactions(transaction)
+		if err != nil {
+			return nil, err
+		}
+		if !transaction.VerifyWithSigner(prevTXs, bc.Signer) {
+			return nil, fmt.Errorf("blockchain: transaction %x failed verification", transaction.ID)
+		}
+
+		for _, vin := range transaction.Input {
+			spentKey := fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)
+			if spentInBlock[spentKey] {
+				return nil, fmt.Errorf("blockchain: transaction %x double-spends %s earlier in the same block", transaction.ID, spentKey)
+			}
+			if !utxoSet.IsUnspent(vin.Txid, vin.Vout) {
+				return nil, fmt.Errorf("blockchain: transaction %x spends the already-spent output %s", transaction.ID, spentKey)
+			}
+			spentInBlock[spentKey] = true
+		}
+	}
+
+	var lastHash []byte
+
+	err := bc.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		lastHash = b.Get([]byte(lastHashKey))
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	newBlock := NewBlock(transactions, lastHash, validator, slot, vrfProof)
+
+	err = bc.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+
+		if err := b.Put(newBlock.Hash, newBlock.Serialize()); err != nil {
+			return err
+		}
+		if err := b.Put([]byte(lastHashKey), newBlock.Hash); err != nil {
+			return err
+		}
+
+		bc.Tip = newBlock.Hash
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	utxoSet.Update(newBlock)
+
+	return newBlock, nil
+}
+
+// FindTransaction searches the chain, from the tip backwards, for the
+// transaction with the given ID.
+func (bc *Blockchain) FindTransaction(id []byte) (Transaction, error) {
+	iter := bc.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, id) {
+				return *tx, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return Transaction{}, errors.New("blockchain: transaction not found")
+}
+
+// prevTransactions builds the prevTXs map Transaction.Verify needs for tx:
+// every transaction referenced by one of tx's inputs, keyed by hex-encoded
+// txid.
+func (bc *Blockchain) prevTransactions(tx *Transaction) (map[string]Transaction, error) {
+	prevTXs := make(map[string]Transaction)
+
+	for _, vin := range tx.Input {
+		prevTx, err := bc.FindTransaction(vin.Txid)
+		if err != nil {
+			return nil, err
+		}
+		prevTXs[hexID(vin.Txid)] = prevTx
+	}
+
+	return prevTXs, nil
+}
+
+// Iterator returns a BlockchainIterator that walks the chain backwards from
+// the current tip to the genesis block.
+func (bc *Blockchain) Iterator() *BlockchainIterator {
+	return &BlockchainIterator{CurrentHash: bc.Tip, DB: bc.DB}
+}
+
+// BlockchainIterator walks a Blockchain backwards, one block at a time.
+type BlockchainIterator struct {
+	CurrentHash []byte
+	DB          *bolt.DB
+}
+
+// Next returns the current block and advances the iterator to its
+// predecessor.
+func (it *BlockchainIterator) Next() *Block {
+	var block *Block
+
+	err := it.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		decoded, err := DeserializeBlock(b.Get(it.CurrentHash))
+		if err != nil {
+			return err
+		}
+		block = decoded
+		return nil
+	})
+	if err != nil {
+		// Our own on-disk chain is trusted, unlike data received from a peer;
+		// a decode failure here means local corruption, not bad input to
+		// quarantine, so it's still appropriate to fail fast.
+		log.Panic(err)
+	}
+
+	it.CurrentHash = block.PrevBlockHash
+	return block
+}
+
+// FindUTXO scans the entire chain and returns every unspent output, keyed by
+// hex-encoded transaction ID. It is the O(chain) ground truth that
+// UTXOSet.Reindex snapshots into the chainstate bucket.
+func (bc *Blockchain) FindUTXO() map[string][]TxOutput {
+	UTXO := make(map[string][]TxOutput)
+	spentTXOs := make(map[string][]int)
+
+	iter := bc.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			txID := hex.EncodeToString(tx.ID)
+
+		Outputs:
+			for outIdx, out := range tx.Output {
+				for _, spentOutIdx := range spentTXOs[txID] {
+					if spentOutIdx == outIdx {
+						continue Outputs
+					}
+				}
+				UTXO[txID] = append(UTXO[txID], out)
+			}
+
+			if !tx.IsCoinbase() {
+				for _, in := range tx.Input {
+					inTxID := hex.EncodeToString(in.Txid)
+					spentTXOs[inTxID] = append(spentTXOs[inTxID], in.Vout)
+				}
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return UTXO
+}
@@ -0,0 +1,184 @@
+package blockchain
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
+)
+
+// withTempBlockchainDir chdirs into a fresh temporary directory for the
+// duration of the test, since Blockchain always opens its BoltDB file at the
+// fixed relative path dbFile.
+func withTempBlockchainDir(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("restore cwd: %v", err)
+		}
+	})
+}
+
+// TestAddBlockRejectsInvalidTransaction guards the integration point where
+// transactions are actually persisted: a non-coinbase transaction that
+// fails Verify must not be accepted into the chain or folded into the UTXO
+// set.
+func TestAddBlockRejectsInvalidTransaction(t *testing.T) {
+	withTempBlockchainDir(t)
+
+	ownerWallet := wallet.NewWallet()
+	bc := InitBlockchain(ownerWallet.GetAddress(), LegacySigner{})
+	defer bc.DB.Close()
+
+	coinbase := bc.Iterator().Next().Transactions[0]
+
+	attackerPriv, attackerPub := newTestKey(t)
+	theft := &Transaction{
+		Input:  []TxInput{{Txid: coinbase.ID, Vout: 0, PubKey: attackerPub}},
+		Output: []TxOutput{{Value: subsidy, PubKeyHash: wallet.HashPubKey(attackerPub)}},
+	}
+	theft.ID = theft.Hash()
+	theft.Sign(attackerPriv, map[string]Transaction{hexID(coinbase.ID): *coinbase}, LegacySigner{})
+
+	if _, err := bc.AddBlock([]*Transaction{theft}, ownerWallet.GetAddress(), 1, nil); err == nil {
+		t.Fatal("AddBlock accepted a transaction that fails Verify")
+	}
+}
+
+// TestAddBlockUpdatesUTXOSet checks the happy path end to end: a valid
+// spend is accepted, the spent output disappears from the UTXO set, and the
+// new output appears in its place.
+func TestAddBlockUpdatesUTXOSet(t *testing.T) {
+	withTempBlockchainDir(t)
+
+	ownerWallet := wallet.NewWallet()
+	bc := InitBlockchain(ownerWallet.GetAddress(), LegacySigner{})
+	defer bc.DB.Close()
+
+	coinbase := bc.Iterator().Next().Transactions[0]
+
+	recipientWallet := wallet.NewWallet()
+	spend := &Transaction{
+		Input:  []TxInput{{Txid: coinbase.ID, Vout: 0, PubKey: ownerWallet.PublicKey}},
+		Output: []TxOutput{{Value: subsidy, PubKeyHash: wallet.HashPubKey(recipientWallet.PublicKey)}},
+	}
+	spend.ID = spend.Hash()
+	spend.Sign(ownerWallet.PrivateKey, map[string]Transaction{hexID(coinbase.ID): *coinbase}, LegacySigner{})
+
+	if _, err := bc.AddBlock([]*Transaction{spend}, ownerWallet.GetAddress(), 1, nil); err != nil {
+		t.Fatalf("AddBlock rejected a valid transaction: %v", err)
+	}
+
+	utxoSet := UTXOSet{Blockchain: bc}
+
+	if utxos := utxoSet.FindUTXO(wallet.HashPubKey(ownerWallet.PublicKey)); len(utxos) != 0 {
+		t.Fatalf("owner's spent coinbase output is still in the UTXO set: %+v", utxos)
+	}
+
+	recipientUTXOs := utxoSet.FindUTXO(wallet.HashPubKey(recipientWallet.PublicKey))
+	if len(recipientUTXOs) != 1 || recipientUTXOs[0].Value != subsidy {
+		t.Fatalf("recipient's new output not found in UTXO set: %+v", recipientUTXOs)
+	}
+}
+
+// TestAddBlockRejectsDoubleSpendAcrossBlocks checks that spending the same
+// output twice in two different blocks is rejected on the second attempt
+// (not merely accepted and then left to crash UTXOSet.Update), even though
+// FindTransaction would still happily find the original transaction earlier
+// in the chain's history.
+func TestAddBlockRejectsDoubleSpendAcrossBlocks(t *testing.T) {
+	withTempBlockchainDir(t)
+
+	ownerWallet := wallet.NewWallet()
+	bc := InitBlockchain(ownerWallet.GetAddress(), LegacySigner{})
+	defer bc.DB.Close()
+
+	coinbase := bc.Iterator().Next().Transactions[0]
+
+	recipientWallet := wallet.NewWallet()
+	spendOnce := func() *Transaction {
+		spend := &Transaction{
+			Input:  []TxInput{{Txid: coinbase.ID, Vout: 0, PubKey: ownerWallet.PublicKey}},
+			Output: []TxOutput{{Value: subsidy, PubKeyHash: wallet.HashPubKey(recipientWallet.PublicKey)}},
+		}
+		spend.ID = spend.Hash()
+		spend.Sign(ownerWallet.PrivateKey, map[string]Transaction{hexID(coinbase.ID): *coinbase}, LegacySigner{})
+		return spend
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{spendOnce()}, ownerWallet.GetAddress(), 1, nil); err != nil {
+		t.Fatalf("AddBlock rejected the first spend of the coinbase output: %v", err)
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{spendOnce()}, ownerWallet.GetAddress(), 2, nil); err == nil {
+		t.Fatal("AddBlock accepted a second spend of an already-spent coinbase output")
+	}
+}
+
+// TestAddBlockRejectsDoubleSpendWithinBlock checks that two transactions in
+// the very same block spending the same output are rejected too, since the
+// UTXO set isn't updated until after the whole block has been validated.
+func TestAddBlockRejectsDoubleSpendWithinBlock(t *testing.T) {
+	withTempBlockchainDir(t)
+
+	ownerWallet := wallet.NewWallet()
+	bc := InitBlockchain(ownerWallet.GetAddress(), LegacySigner{})
+	defer bc.DB.Close()
+
+	coinbase := bc.Iterator().Next().Transactions[0]
+	recipientWallet := wallet.NewWallet()
+
+	newSpend := func() *Transaction {
+		spend := &Transaction{
+			Input:  []TxInput{{Txid: coinbase.ID, Vout: 0, PubKey: ownerWallet.PublicKey}},
+			Output: []TxOutput{{Value: subsidy, PubKeyHash: wallet.HashPubKey(recipientWallet.PublicKey)}},
+		}
+		spend.ID = spend.Hash()
+		spend.Sign(ownerWallet.PrivateKey, map[string]Transaction{hexID(coinbase.ID): *coinbase}, LegacySigner{})
+		return spend
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{newSpend(), newSpend()}, ownerWallet.GetAddress(), 1, nil); err == nil {
+		t.Fatal("AddBlock accepted two transactions in the same block spending the same output")
+	}
+}
+
+// TestAddBlockRejectsWrongChainID checks that AddBlock enforces the chain's
+// own pinned Signer rather than trusting whatever chain ID a transaction's
+// signature claims: a transaction signed for chain 1337 must be rejected by
+// a Blockchain pinned to chain 1, even though Transaction.Verify alone would
+// happily accept it.
+func TestAddBlockRejectsWrongChainID(t *testing.T) {
+	withTempBlockchainDir(t)
+
+	ownerWallet := wallet.NewWallet()
+	bc := InitBlockchain(ownerWallet.GetAddress(), NewChainIDSigner(big.NewInt(1)))
+	defer bc.DB.Close()
+
+	coinbase := bc.Iterator().Next().Transactions[0]
+
+	recipientWallet := wallet.NewWallet()
+	spend := &Transaction{
+		Input:  []TxInput{{Txid: coinbase.ID, Vout: 0, PubKey: ownerWallet.PublicKey}},
+		Output: []TxOutput{{Value: subsidy, PubKeyHash: wallet.HashPubKey(recipientWallet.PublicKey)}},
+	}
+	spend.ID = spend.Hash()
+	spend.Sign(ownerWallet.PrivateKey, map[string]Transaction{hexID(coinbase.ID): *coinbase}, NewChainIDSigner(big.NewInt(1337)))
+
+	if !spend.Verify(map[string]Transaction{hexID(coinbase.ID): *coinbase}) {
+		t.Fatal("sanity check failed: Verify should accept the self-reported chain ID")
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{spend}, ownerWallet.GetAddress(), 1, nil); err == nil {
+		t.Fatal("AddBlock accepted a transaction signed for a different chain ID")
+	}
+}
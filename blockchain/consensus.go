@@ -1,75 +1,165 @@
 package blockchain
 
 import (
-    "crypto/rand"  // Cryptographically secure random (GOOD for blockchain!)
-    "log"
-    "math/big"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
 )
 
-// POSValidator represents a validator in the Proof of Stake consensus system.
-// Each validator is identified by their public key and has a stake (amount of tokens locked).
+// POSValidator represents a validator in the Proof of Stake consensus
+// system, identified by its public key and staked weight. It also holds the
+// matching private key so it can produce VRF proofs when it proposes a
+// block; in a real multi-node deployment only the proposer itself would hold
+// this, but this package simulates the whole validator set in one process.
 type POSValidator struct {
-    PublicKey []byte  // Unique identifier for the validator
-    Stake     int     // Amount of tokens staked (higher stake = higher chance of selection)
+	PrivateKey ecdsa.PrivateKey
+	PublicKey  []byte
+	Stake      int
 }
 
-// ProofOfStake selects a validator to create the next block using weighted random selection.
-// The selection probability is proportional to each validator's stake.
-// 
-// Algorithm:
-// 1. Calculate the total stake across all validators
-// 2. Generate a CRYPTOGRAPHICALLY SECURE random number between 0 and totalStake
-// 3. Iterate through validators, subtracting their stake from the random number
-// 4. When the random number reaches 0 or below, that validator is selected
-//
-// Example: If Validator A has 70 tokens and Validator B has 30 tokens:
-// - Total stake = 100
-// - Random number between 0-99
-// - If random = 45, Validator A is selected (45 < 70)
-// - If random = 85, Validator B is selected (85-70=15, 15 < 30)
-//
-// Security Note:
-//   Uses crypto/rand instead of math/rand to prevent validator selection manipulation.
-//   Math/rand is predictable and can be exploited in blockchain systems.
-//
-// Parameters:
-//   validators: map of validator addresses to their POSValidator structs
+// NewPOSValidator generates a fresh keypair for a validator staking the
+// given amount.
+func NewPOSValidator(stake int) *POSValidator {
+	w := wallet.NewWallet()
+	return &POSValidator{PrivateKey: w.PrivateKey, PublicKey: w.PublicKey, Stake: stake}
+}
+
+// ============================================================================
+// VRF-BASED LEADER SELECTION
+// ============================================================================
 //
-// Returns:
-//   string: the public key of the selected validator
-func ProofOfStake(validators map[string]*POSValidator) string {
-    // Step 1: Calculate the total stake of all validators
-    // This gives us the range for our weighted random selection
-    totalStake := 0
-    for _, validator := range validators {
-        totalStake += validator.Stake
-    }
-
-    // Step 2: Generate a cryptographically secure random number in the range [0, totalStake)
-    // crypto/rand provides unpredictable randomness (essential for fair validator selection)
-    randomBig, err := rand.Int(rand.Reader, big.NewInt(int64(totalStake)))
-    if err != nil {
-        log.Panic(err)
-    }
-    
-    // Convert big.Int to int64 for our selection algorithm
-    random := randomBig.Int64()
-    
-    // Step 3: Weighted selection - iterate through validators
-    // Subtract each validator's stake from our random number
-    // The first validator that brings random to 0 or below wins
-    for _, validator := range validators {
-        random -= int64(validator.Stake)  // Cast to int64 for proper subtraction
-        
-        // If random is 0 or negative, this validator is selected
-        // This gives validators with higher stakes a proportionally higher chance
-        if random <= 0 {
-            return string(validator.PublicKey)
-        }
-    }
-    
-    // This should never happen if totalStake > 0 and validators exist
-    // Panic indicates a critical error in the selection logic
-    log.Panic("Unable to find a validator")
-    return ""
-}
\ No newline at end of file
+// Each validator computes proof = Sign(sk, prevBlockHash || slot) and
+// ticket = SHA256(proof). A validator is eligible to propose at that slot
+// iff ticket < 2^256 * stake/totalStake, i.e. a weighted lottery over the
+// ticket's value. Because proof is a deterministic signature over public
+// data, any other node can recompute ticket from the proof alone and check
+// the same threshold against its own copy of the validator set snapshot at
+// that slot - no need to trust the proposer's own random draw.
+
+// slotMessage builds the data a validator signs to produce its VRF proof for
+// a given slot.
+func slotMessage(prevBlockHash []byte, slot uint64) []byte {
+	slotBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(slotBytes, slot)
+	return append(append([]byte{}, prevBlockHash...), slotBytes...)
+}
+
+// ticketFromProof derives a validator's lottery ticket from its VRF proof.
+func ticketFromProof(proof []byte) *big.Int {
+	hash := sha256.Sum256(proof)
+	return new(big.Int).SetBytes(hash[:])
+}
+
+// ticketSpace is 2^256, the range a SHA-256 ticket is drawn from.
+var ticketSpace = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// isEligible reports whether ticket falls within a validator's
+// stake-weighted share of the ticket space: ticket < ticketSpace * stake /
+// totalStake, computed without floating point as
+// ticket*totalStake < stake*ticketSpace.
+func isEligible(ticket *big.Int, stake, totalStake int) bool {
+	if totalStake <= 0 || stake <= 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(ticket, big.NewInt(int64(totalStake)))
+	rhs := new(big.Int).Mul(big.NewInt(int64(stake)), ticketSpace)
+	return lhs.Cmp(rhs) < 0
+}
+
+// signProof ECDSA-signs data and packs the signature into the same
+// fixed-width (r, s) layout used elsewhere in the package.
+func signProof(privKey ecdsa.PrivateKey, data []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, &privKey, data)
+	if err != nil {
+		log.Panic(err)
+	}
+	return append(leftPad(r.Bytes(), rsByteLen), leftPad(s.Bytes(), rsByteLen)...)
+}
+
+// verifyProof checks an ECDSA signature produced by signProof.
+func verifyProof(pubKey []byte, data []byte, proof []byte) bool {
+	if len(proof) != rsByteLen*2 || len(pubKey) == 0 {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(proof[:rsByteLen])
+	s := new(big.Int).SetBytes(proof[rsByteLen:])
+
+	x := new(big.Int).SetBytes(pubKey[:len(pubKey)/2])
+	y := new(big.Int).SetBytes(pubKey[len(pubKey)/2:])
+	pub := ecdsa.PublicKey{Curve: ellipticCurve(), X: x, Y: y}
+
+	return ecdsa.Verify(&pub, data, r, s)
+}
+
+// ProposeBlock computes validator's VRF proof for the given slot and reports
+// whether that proof makes it the eligible proposer. Callers should only
+// build and broadcast a block when eligible is true.
+func ProposeBlock(validator *POSValidator, prevBlockHash []byte, slot uint64, totalStake int) (proof []byte, eligible bool) {
+	proof = signProof(validator.PrivateKey, slotMessage(prevBlockHash, slot))
+	eligible = isEligible(ticketFromProof(proof), validator.Stake, totalStake)
+	return proof, eligible
+}
+
+// VerifyEligibility re-derives a block proposer's ticket from its VRF proof
+// and checks both that the proof was produced by pubKey and that the
+// resulting ticket clears the stake-weighted threshold. Any node holding the
+// validator set snapshot for this slot can run this independently of the
+// proposer.
+func VerifyEligibility(pubKey []byte, stake, totalStake int, prevBlockHash []byte, slot uint64, proof []byte) bool {
+	if !verifyProof(pubKey, slotMessage(prevBlockHash, slot), proof) {
+		return false
+	}
+	return isEligible(ticketFromProof(proof), stake, totalStake)
+}
+
+// ============================================================================
+// SLASHING FOR EQUIVOCATION
+// ============================================================================
+
+// SlashingTracker watches proposed blocks for equivocation: a validator
+// signing two different valid blocks for the same slot. It is not safe for
+// concurrent use.
+type SlashingTracker struct {
+	// seenBlockBySlot maps "validatorPubKeyHex:slot" to the hash of the
+	// first block observed from that validator at that slot.
+	seenBlockBySlot map[string]string
+}
+
+// NewSlashingTracker returns an empty SlashingTracker.
+func NewSlashingTracker() *SlashingTracker {
+	return &SlashingTracker{seenBlockBySlot: make(map[string]string)}
+}
+
+// Observe records block as proposed by its Validator for its Slot. If a
+// different block from the same validator was already observed for that
+// slot, the validator has equivocated: its stake is burned (zeroed) in
+// validators and Observe reports equivocated=true.
+func (st *SlashingTracker) Observe(validators map[string]*POSValidator, block *Block) (equivocated bool) {
+	// validators is keyed by the raw public key bytes (as a string), matching
+	// the convention used throughout this package's validator-set maps.
+	validatorKey := string(block.Validator)
+	slotKey := fmt.Sprintf("%s:%d", hex.EncodeToString(block.Validator), block.Slot)
+	blockHash := hex.EncodeToString(block.Hash)
+
+	prevHash, seen := st.seenBlockBySlot[slotKey]
+	if !seen {
+		st.seenBlockBySlot[slotKey] = blockHash
+		return false
+	}
+	if prevHash == blockHash {
+		return false
+	}
+
+	if v, ok := validators[validatorKey]; ok {
+		v.Stake = 0
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestIsEligibleThresholdEdges pins down the exact boundary of the
+// stake-weighted lottery: a ticket strictly below a validator's share of
+// ticketSpace is eligible, a ticket at or above it is not.
+func TestIsEligibleThresholdEdges(t *testing.T) {
+	const stake = 1
+	const totalStake = 4
+
+	// share = ticketSpace * stake / totalStake = ticketSpace / 4.
+	share := new(big.Int).Div(ticketSpace, big.NewInt(totalStake))
+
+	justBelow := new(big.Int).Sub(share, big.NewInt(1))
+	if !isEligible(justBelow, stake, totalStake) {
+		t.Errorf("ticket just below the stake-weighted share was not eligible")
+	}
+
+	if isEligible(share, stake, totalStake) {
+		t.Errorf("ticket exactly at the stake-weighted share was eligible, want ineligible")
+	}
+
+	justAbove := new(big.Int).Add(share, big.NewInt(1))
+	if isEligible(justAbove, stake, totalStake) {
+		t.Errorf("ticket just above the stake-weighted share was eligible")
+	}
+}
+
+// TestIsEligibleDegenerateStake checks the guard against nonsensical inputs:
+// nothing is eligible with zero or negative stake or total stake.
+func TestIsEligibleDegenerateStake(t *testing.T) {
+	ticket := big.NewInt(0)
+
+	cases := []struct {
+		stake, totalStake int
+	}{
+		{0, 10},
+		{10, 0},
+		{-1, 10},
+		{10, -1},
+	}
+	for _, c := range cases {
+		if isEligible(ticket, c.stake, c.totalStake) {
+			t.Errorf("isEligible(0, stake=%d, totalStake=%d) = true, want false", c.stake, c.totalStake)
+		}
+	}
+}
+
+// TestProposeVerifyEligibilityRoundTrip checks that a validator's own
+// proof is accepted by VerifyEligibility with its real stake, and rejected
+// both under a different validator's public key and with zero stake.
+func TestProposeVerifyEligibilityRoundTrip(t *testing.T) {
+	const totalStake = 100
+	validator := NewPOSValidator(totalStake)
+	other := NewPOSValidator(totalStake)
+
+	prevHash := []byte("prevblockhash")
+	var slot uint64 = 7
+
+	proof, eligible := ProposeBlock(validator, prevHash, slot, totalStake)
+	if !eligible {
+		t.Skip("validator happened not to be drawn for this slot; threshold edges are covered separately")
+	}
+
+	if !VerifyEligibility(validator.PublicKey, validator.Stake, totalStake, prevHash, slot, proof) {
+		t.Error("VerifyEligibility rejected the validator's own valid proof")
+	}
+
+	if VerifyEligibility(other.PublicKey, validator.Stake, totalStake, prevHash, slot, proof) {
+		t.Error("VerifyEligibility accepted a proof under a different validator's public key")
+	}
+
+	if VerifyEligibility(validator.PublicKey, 0, totalStake, prevHash, slot, proof) {
+		t.Error("VerifyEligibility accepted a proof for a validator with zero stake")
+	}
+}
+
+// TestSlashingTrackerDetectsEquivocation checks that a validator signing
+// two different blocks for the same slot gets its stake burned and is
+// reported as having equivocated, while distinct slots and re-observing the
+// same block are both left alone.
+func TestSlashingTrackerDetectsEquivocation(t *testing.T) {
+	validator := NewPOSValidator(50)
+	validators := map[string]*POSValidator{string(validator.PublicKey): validator}
+	tracker := NewSlashingTracker()
+
+	blockA := &Block{Hash: []byte("block-a"), Validator: validator.PublicKey, Slot: 1}
+	blockB := &Block{Hash: []byte("block-b"), Validator: validator.PublicKey, Slot: 1}
+	blockOtherSlot := &Block{Hash: []byte("block-c"), Validator: validator.PublicKey, Slot: 2}
+
+	if tracker.Observe(validators, blockA) {
+		t.Error("first block observed for a slot was reported as equivocation")
+	}
+	if tracker.Observe(validators, blockA) {
+		t.Error("re-observing the same block was reported as equivocation")
+	}
+	if tracker.Observe(validators, blockOtherSlot) {
+		t.Error("a block for a different slot was reported as equivocation")
+	}
+	if validator.Stake == 0 {
+		t.Fatal("stake was burned before any equivocation was observed")
+	}
+
+	if !tracker.Observe(validators, blockB) {
+		t.Error("a second distinct block for the same slot was not reported as equivocation")
+	}
+	if validator.Stake != 0 {
+		t.Errorf("equivocating validator's stake = %d, want 0", validator.Stake)
+	}
+}
@@ -0,0 +1,142 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// MerkleNode is a single node of a MerkleTree: a leaf hashes a transaction
+// ID directly, an interior node hashes the concatenation of its children.
+type MerkleNode struct {
+	Left  *MerkleNode
+	Right *MerkleNode
+	Hash  []byte
+}
+
+// NewMerkleNode builds a leaf node hashing data when left and right are
+// nil, or an interior node hashing left.Hash||right.Hash otherwise.
+func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
+	node := &MerkleNode{Left: left, Right: right}
+
+	if left == nil && right == nil {
+		hash := sha256.Sum256(data)
+		node.Hash = hash[:]
+		return node
+	}
+
+	hash := sha256.Sum256(append(append([]byte{}, left.Hash...), right.Hash...))
+	node.Hash = hash[:]
+	return node
+}
+
+// MerkleTree is a binary hash tree over a block's transaction IDs, used both
+// to produce a compact root commitment and to generate SPV inclusion
+// proofs.
+type MerkleTree struct {
+	Root *MerkleNode
+}
+
+// NewMerkleTree builds a MerkleTree over txIDs, duplicating the last node at
+// any level with an odd number of nodes, per Bitcoin convention.
+func NewMerkleTree(txIDs [][]byte) *MerkleTree {
+	if len(txIDs) == 0 {
+		return &MerkleTree{Root: NewMerkleNode(nil, nil, []byte{})}
+	}
+
+	nodes := make([]*MerkleNode, len(txIDs))
+	for i, id := range txIDs {
+		nodes[i] = NewMerkleNode(nil, nil, id)
+	}
+
+	for len(nodes) > 1 {
+		if len(nodes)%2 != 0 {
+			nodes = append(nodes, nodes[len(nodes)-1])
+		}
+
+		var level []*MerkleNode
+		for i := 0; i < len(nodes); i += 2 {
+			level = append(level, NewMerkleNode(nodes[i], nodes[i+1], nil))
+		}
+		nodes = level
+	}
+
+	return &MerkleTree{Root: nodes[0]}
+}
+
+// MerkleRoot returns the Merkle root of the block's transaction IDs.
+func (b *Block) MerkleRoot() []byte {
+	var txIDs [][]byte
+	for _, tx := range b.Transactions {
+		txIDs = append(txIDs, tx.ID)
+	}
+	return NewMerkleTree(txIDs).Root.Hash
+}
+
+// BuildMerkleProof returns an SPV inclusion proof for txID: the sibling
+// hash at each level of the tree needed to recompute the Merkle root, and
+// for each one whether it sits to the right of the node being proved (so
+// VerifyMerkleProof knows which side to concatenate it on).
+func (b *Block) BuildMerkleProof(txID []byte) ([][]byte, []bool, error) {
+	leafIndex := -1
+	level := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hash := sha256.Sum256(tx.ID)
+		level[i] = hash[:]
+		if bytes.Equal(tx.ID, txID) {
+			leafIndex = i
+		}
+	}
+
+	if leafIndex == -1 {
+		return nil, nil, fmt.Errorf("blockchain: transaction %x not found in block", txID)
+	}
+
+	var siblings [][]byte
+	var siblingIsRight []bool
+
+	index := leafIndex
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		siblingIndex := index ^ 1
+		siblings = append(siblings, level[siblingIndex])
+		siblingIsRight = append(siblingIsRight, siblingIndex > index)
+
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			hash := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, hash[:])
+		}
+		level = next
+		index /= 2
+	}
+
+	return siblings, siblingIsRight, nil
+}
+
+// VerifyMerkleProof recomputes the Merkle root from txID and the sibling
+// path produced by BuildMerkleProof, and reports whether it matches root.
+func VerifyMerkleProof(txID, root []byte, siblings [][]byte, isRight []bool) bool {
+	if len(siblings) != len(isRight) {
+		return false
+	}
+
+	hash := sha256.Sum256(txID)
+	current := hash[:]
+
+	for i, sibling := range siblings {
+		var combined []byte
+		if isRight[i] {
+			combined = append(append([]byte{}, current...), sibling...)
+		} else {
+			combined = append(append([]byte{}, sibling...), current...)
+		}
+		next := sha256.Sum256(combined)
+		current = next[:]
+	}
+
+	return bytes.Equal(current, root)
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import "testing"
+
+func blockWithTxIDs(ids ...string) *Block {
+	b := &Block{}
+	for _, id := range ids {
+		b.Transactions = append(b.Transactions, &Transaction{ID: []byte(id)})
+	}
+	return b
+}
+
+// TestMerkleProofRoundTrip checks that every transaction in a block,
+// including an odd count where the last node gets duplicated, produces a
+// proof that verifies against the block's own Merkle root.
+func TestMerkleProofRoundTrip(t *testing.T) {
+	for _, ids := range [][]string{
+		{"tx0"},
+		{"tx0", "tx1"},
+		{"tx0", "tx1", "tx2"},
+		{"tx0", "tx1", "tx2", "tx3", "tx4"},
+	} {
+		b := blockWithTxIDs(ids...)
+		root := b.MerkleRoot()
+
+		for _, id := range ids {
+			siblings, isRight, err := b.BuildMerkleProof([]byte(id))
+			if err != nil {
+				t.Fatalf("BuildMerkleProof(%q): %v", id, err)
+			}
+			if !VerifyMerkleProof([]byte(id), root, siblings, isRight) {
+				t.Errorf("VerifyMerkleProof rejected a genuine proof for %q in block of size %d", id, len(ids))
+			}
+		}
+	}
+}
+
+// TestMerkleProofDetectsTamperedLeaf checks that a proof built for one
+// transaction doesn't verify when checked against a different transaction
+// ID, i.e. the leaf can't be swapped for another without invalidating the
+// proof.
+func TestMerkleProofDetectsTamperedLeaf(t *testing.T) {
+	b := blockWithTxIDs("tx0", "tx1", "tx2", "tx3")
+	root := b.MerkleRoot()
+
+	siblings, isRight, err := b.BuildMerkleProof([]byte("tx1"))
+	if err != nil {
+		t.Fatalf("BuildMerkleProof: %v", err)
+	}
+
+	if VerifyMerkleProof([]byte("tx2"), root, siblings, isRight) {
+		t.Error("VerifyMerkleProof accepted tx1's proof for a different transaction ID")
+	}
+}
+
+// TestMerkleProofDetectsTamperedSibling checks that flipping a single byte
+// of a sibling hash in the proof path invalidates it.
+func TestMerkleProofDetectsTamperedSibling(t *testing.T) {
+	b := blockWithTxIDs("tx0", "tx1", "tx2", "tx3")
+	root := b.MerkleRoot()
+
+	siblings, isRight, err := b.BuildMerkleProof([]byte("tx0"))
+	if err != nil {
+		t.Fatalf("BuildMerkleProof: %v", err)
+	}
+	if len(siblings) == 0 {
+		t.Fatal("expected at least one sibling in the proof")
+	}
+
+	tampered := append([]byte{}, siblings[0]...)
+	tampered[0] ^= 0xFF
+	siblings[0] = tampered
+
+	if VerifyMerkleProof([]byte("tx0"), root, siblings, isRight) {
+		t.Error("VerifyMerkleProof accepted a proof with a tampered sibling hash")
+	}
+}
+
+// TestMerkleProofDetectsTamperedRoot checks that a genuine proof doesn't
+// verify against a root it wasn't built for.
+func TestMerkleProofDetectsTamperedRoot(t *testing.T) {
+	b := blockWithTxIDs("tx0", "tx1")
+	siblings, isRight, err := b.BuildMerkleProof([]byte("tx0"))
+	if err != nil {
+		t.Fatalf("BuildMerkleProof: %v", err)
+	}
+
+	forgedRoot := blockWithTxIDs("other0", "other1").MerkleRoot()
+	if VerifyMerkleProof([]byte("tx0"), forgedRoot, siblings, isRight) {
+		t.Error("VerifyMerkleProof accepted a proof against an unrelated root")
+	}
+}
+
+// TestBuildMerkleProofUnknownTxID checks that BuildMerkleProof errors out
+// rather than returning a bogus proof for a transaction not in the block.
+func TestBuildMerkleProofUnknownTxID(t *testing.T) {
+	b := blockWithTxIDs("tx0", "tx1")
+	if _, _, err := b.BuildMerkleProof([]byte("not-in-block")); err == nil {
+		t.Error("BuildMerkleProof succeeded for a transaction ID not in the block")
+	}
+}
+
+// TestVerifyMerkleProofMismatchedLengths checks the defensive length check
+// between the siblings and isRight slices.
+func TestVerifyMerkleProofMismatchedLengths(t *testing.T) {
+	if VerifyMerkleProof([]byte("tx0"), []byte("root"), [][]byte{[]byte("sib")}, nil) {
+		t.Error("VerifyMerkleProof accepted mismatched siblings/isRight lengths")
+	}
+}
@@ -0,0 +1,388 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
+)
+
+// ellipticCurve returns the curve used throughout the blockchain package for
+// ECDSA keys and signatures.
+func ellipticCurve() elliptic.Curve {
+	return elliptic.P256()
+}
+
+// Signer abstracts the policy used to hash and sign a transaction, so that
+// new signing schemes (e.g. replay protection across chains) can be added
+// without changing Transaction's internals. It is deliberately analogous to
+// go-ethereum's Signer: Hash picks the digest that gets signed, Sender
+// recovers who signed it, and SignatureValues decodes the wire signature
+// format into its (r, s, v) components.
+type Signer interface {
+	// Hash returns the digest that should be signed/verified for tx.
+	Hash(tx *Transaction) []byte
+	// Sender returns the public key hash of whoever signed tx.
+	Sender(tx *Transaction) ([]byte, error)
+	// SignatureValues decodes a stored signature into its r, s and v parts.
+	SignatureValues(sig []byte) (r, s, v *big.Int, err error)
+}
+
+// ErrInvalidSig is returned when a stored signature cannot be decoded into
+// (r, s, v), or decodes into a v that the signer doesn't recognize.
+var ErrInvalidSig = errors.New("blockchain: invalid transaction signature")
+
+// rsByteLen is the fixed width, in bytes, each of r and s is padded to before
+// being written into a stored signature. v follows as a length-prefixed tail
+// so chain IDs of any size can be encoded.
+const rsByteLen = 32
+
+// encodeSignature packs r, s and v into the wire format shared by every
+// Signer implementation: r (32 bytes) || s (32 bytes) || len(v) (1 byte) || v.
+func encodeSignature(r, s, v *big.Int) []byte {
+	sig := make([]byte, 0, rsByteLen*2+1)
+	sig = append(sig, leftPad(r.Bytes(), rsByteLen)...)
+	sig = append(sig, leftPad(s.Bytes(), rsByteLen)...)
+
+	vBytes := v.Bytes()
+	sig = append(sig, byte(len(vBytes)))
+	sig = append(sig, vBytes...)
+
+	return sig
+}
+
+// parseSignature is the inverse of encodeSignature, shared by every Signer
+// implementation since the wire layout doesn't depend on signing policy.
+func parseSignature(sig []byte) (r, s, v *big.Int, err error) {
+	if len(sig) < rsByteLen*2+1 {
+		return nil, nil, nil, ErrInvalidSig
+	}
+
+	r = new(big.Int).SetBytes(sig[:rsByteLen])
+	s = new(big.Int).SetBytes(sig[rsByteLen : rsByteLen*2])
+
+	vLen := int(sig[rsByteLen*2])
+	if len(sig) != rsByteLen*2+1+vLen {
+		return nil, nil, nil, ErrInvalidSig
+	}
+	v = new(big.Int).SetBytes(sig[rsByteLen*2+1:])
+
+	return r, s, v, nil
+}
+
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b[len(b)-size:]
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// senderFromInputs recovers the signer's public key hash from the first
+// input of tx. Every input in a transaction signed through NewTransaction
+// carries the spender's raw public key alongside its signature, so recovery
+// is a straight hash rather than an elliptic-curve point recovery.
+func senderFromInputs(tx *Transaction) ([]byte, error) {
+	if len(tx.Input) == 0 || len(tx.Input[0].PubKey) == 0 {
+		return nil, errors.New("blockchain: transaction has no signing input")
+	}
+	return HashPubKey(tx.Input[0].PubKey), nil
+}
+
+// ============================================================================
+// LegacySigner
+// ============================================================================
+
+// LegacySigner reproduces today's signing behaviour: the digest is a plain
+// hash of the trimmed transaction, and v only ever carries the ECDSA
+// recovery bit (0 or 1), with no chain binding. Transactions signed this way
+// can be replayed on any chain that accepts LegacySigner.
+type LegacySigner struct{}
+
+// Hash expects tx to already be the per-input digest built by sighash (a
+// trimmed copy with exactly one input's PubKey substituted for the
+// PubKeyHash of the output it spends); it hashes tx as given rather than
+// trimming again, so that substitution actually reaches the signed digest.
+func (LegacySigner) Hash(tx *Transaction) []byte {
+	hash := sha256.Sum256(tx.Serialize())
+	return hash[:]
+}
+
+func (LegacySigner) Sender(tx *Transaction) ([]byte, error) {
+	return senderFromInputs(tx)
+}
+
+func (LegacySigner) SignatureValues(sig []byte) (r, s, v *big.Int, err error) {
+	r, s, v, err = parseSignature(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if v.Cmp(big.NewInt(1)) > 0 || v.Sign() < 0 {
+		return nil, nil, nil, ErrInvalidSig
+	}
+	return r, s, v, nil
+}
+
+// ============================================================================
+// ChainIDSigner
+// ============================================================================
+
+// ChainIDSigner mixes a chain ID into the signing digest and encodes it into
+// v as 2*chainID + 35 + recoveryBit, mirroring EIP-155. A signature produced
+// for one chain ID fails verification under any other, preventing a
+// transaction signed for, say, the test chain from being replayed on prod.
+type ChainIDSigner struct {
+	ChainID *big.Int
+}
+
+// NewChainIDSigner returns a ChainIDSigner bound to chainID.
+func NewChainIDSigner(chainID *big.Int) ChainIDSigner {
+	return ChainIDSigner{ChainID: chainID}
+}
+
+// Hash expects tx to already be the per-input digest built by sighash (a
+// trimmed copy with exactly one input's PubKey substituted for the
+// PubKeyHash of the output it spends); it hashes tx as given rather than
+// trimming again, so that substitution actually reaches the signed digest.
+func (s ChainIDSigner) Hash(tx *Transaction) []byte {
+	hash := sha256.Sum256(bytes.Join([][]byte{
+		tx.Serialize(),
+		s.ChainID.Bytes(),
+	}, []byte{}))
+	return hash[:]
+}
+
+func (s ChainIDSigner) Sender(tx *Transaction) ([]byte, error) {
+	return senderFromInputs(tx)
+}
+
+func (s ChainIDSigner) SignatureValues(sig []byte) (r, s_, v *big.Int, err error) {
+	r, s_, v, err = parseSignature(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	recoveryID, chainID := recoverChainID(v)
+	if chainID == nil || chainID.Cmp(s.ChainID) != 0 {
+		return nil, nil, nil, ErrInvalidSig
+	}
+	_ = recoveryID
+
+	return r, s_, v, nil
+}
+
+// chainIDOffset is the constant term in v = 2*chainID + 35 + recoveryBit.
+const chainIDOffset = 35
+
+// encodeChainIDV computes the EIP-155-style v for a given chain ID and
+// recovery bit.
+func encodeChainIDV(chainID *big.Int, recoveryBit byte) *big.Int {
+	v := new(big.Int).Mul(chainID, big.NewInt(2))
+	v.Add(v, big.NewInt(int64(chainIDOffset)+int64(recoveryBit)))
+	return v
+}
+
+// recoverChainID reverses encodeChainIDV, returning the recovery bit and
+// chain ID encoded into v. It returns a nil chainID if v does not look like
+// an EIP-155-style value (i.e. v < 35).
+func recoverChainID(v *big.Int) (recoveryBit byte, chainID *big.Int) {
+	if v.Cmp(big.NewInt(chainIDOffset)) < 0 {
+		return 0, nil
+	}
+
+	adjusted := new(big.Int).Sub(v, big.NewInt(chainIDOffset))
+	recoveryBit = byte(new(big.Int).Mod(adjusted, big.NewInt(2)).Int64())
+	chainID = new(big.Int).Div(adjusted, big.NewInt(2))
+	return recoveryBit, chainID
+}
+
+// deriveSigner inspects a decoded v value and returns the Signer that
+// produced it: a ChainIDSigner if v encodes a chain ID (v >= 35), otherwise
+// LegacySigner. This lets DeserializeTransaction (and Transaction.Verify)
+// pick the right hashing policy without the caller having to know in
+// advance which scheme signed a given transaction.
+func deriveSigner(v *big.Int) Signer {
+	if _, chainID := recoverChainID(v); chainID != nil {
+		return ChainIDSigner{ChainID: chainID}
+	}
+	return LegacySigner{}
+}
+
+// ============================================================================
+// Signing with a Signer
+// ============================================================================
+
+// NewTransaction builds a transaction sending amount from address "from" to
+// address "to" and signs it under signer's hashing policy. from must have a
+// wallet in wallets; inputs must already reference the previous outputs
+// being spent (Txid, Vout and the spender's raw PubKey, found via
+// wallets.GetWallet(from).PublicKey); prevTXs maps each referenced txid (hex
+// encoded) to the transaction that produced it.
+func NewTransaction(from, to []byte, amount int, wallets *wallet.Wallets, inputs []TxInput, prevTXs map[string]Transaction, signer Signer) (*Transaction, error) {
+	senderWallet, ok := wallets.GetWallet(from)
+	if !ok {
+		return nil, fmt.Errorf("blockchain: no wallet for address %s", from)
+	}
+
+	output, err := NewTXOutput(amount, to)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		Input:  inputs,
+		Output: []TxOutput{*output},
+	}
+	tx.ID = tx.Hash()
+	tx.Sign(senderWallet.PrivateKey, prevTXs, signer)
+
+	return tx, nil
+}
+
+// sighash builds the digest that must be signed/verified for input inID: a
+// single TrimmedCopy of tx (every input's Signature and PubKey cleared),
+// with inID's PubKey then set to spentPubKeyHash, the PubKeyHash of the
+// output it spends. Trimming happens exactly once, here, so the
+// substitution survives into what signer.Hash actually hashes - Signer
+// implementations must hash tx as given, not trim it again.
+func sighash(tx *Transaction, inID int, spentPubKeyHash []byte, signer Signer) []byte {
+	trimmed := tx.TrimmedCopy()
+	trimmed.Input[inID].PubKey = spentPubKeyHash
+	return signer.Hash(&trimmed)
+}
+
+// Sign signs each input of the transaction with privKey using signer's
+// hashing policy, storing a (r, s, v) signature on every input.
+func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction, signer Signer) {
+	if tx.IsCoinbase() {
+		return
+	}
+
+	for inID, vin := range tx.Input {
+		prevTx, ok := prevTXs[hexID(vin.Txid)]
+		if !ok {
+			log.Panic("ERROR: previous transaction is not correct")
+		}
+
+		digest := sighash(tx, inID, prevTx.Output[vin.Vout].PubKeyHash, signer)
+
+		r, s, err := ecdsa.Sign(rand.Reader, &privKey, digest)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		v := signerV(signer)
+		tx.Input[inID].Signature = encodeSignature(r, s, v)
+	}
+}
+
+// VerifyWithSigner checks the signature of every input against the outputs
+// referenced via prevTXs under the given signer's hashing policy. Unlike
+// Verify, it does not try to infer the signing scheme from v, so it is used
+// when the caller already knows which signer produced the transaction.
+func (tx *Transaction) VerifyWithSigner(prevTXs map[string]Transaction, signer Signer) bool {
+	if tx.IsCoinbase() {
+		return true
+	}
+
+	for inID := range tx.Input {
+		if !tx.verifyInput(inID, prevTXs, signer) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Verify checks the signature of every input against the outputs referenced
+// via prevTXs, auto-selecting the Signer that produced each input's
+// signature from its encoded v (see deriveSigner) rather than requiring the
+// caller to already know which scheme signed the transaction. Because the
+// chain ID it checks against is whatever the signature itself claims, this
+// alone gives no replay protection between chains; AddBlock instead calls
+// VerifyWithSigner against the chain's own pinned Signer.
+func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
+	if tx.IsCoinbase() {
+		return true
+	}
+
+	for inID, vin := range tx.Input {
+		_, _, v, err := parseSignature(vin.Signature)
+		if err != nil {
+			return false
+		}
+
+		signer := deriveSigner(v)
+		if !tx.verifyInput(inID, prevTXs, signer) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// verifyInput checks a single input's signature under signer's hashing
+// policy.
+func (tx *Transaction) verifyInput(inID int, prevTXs map[string]Transaction, signer Signer) bool {
+	vin := tx.Input[inID]
+
+	prevTx, ok := prevTXs[hexID(vin.Txid)]
+	if !ok {
+		log.Panic("ERROR: previous transaction is not correct")
+	}
+
+	if vin.Vout < 0 || vin.Vout >= len(prevTx.Output) {
+		return false
+	}
+
+	// A valid (r, s) for whatever PubKey the input itself carries proves
+	// nothing on its own - it must also be the key the referenced output is
+	// actually locked to, or anyone could spend anyone else's UTXOs with a
+	// freshly generated keypair of their own.
+	if !vin.UsesKey(prevTx.Output[vin.Vout].PubKeyHash) {
+		return false
+	}
+
+	r, s, _, err := signer.SignatureValues(vin.Signature)
+	if err != nil {
+		return false
+	}
+
+	digest := sighash(tx, inID, prevTx.Output[vin.Vout].PubKeyHash, signer)
+
+	x := new(big.Int).SetBytes(vin.PubKey[:(len(vin.PubKey) / 2)])
+	y := new(big.Int).SetBytes(vin.PubKey[(len(vin.PubKey) / 2):])
+	pubKey := ecdsa.PublicKey{Curve: ellipticCurve(), X: x, Y: y}
+
+	return ecdsa.Verify(&pubKey, digest, r, s)
+}
+
+// signerV derives the v value a signer embeds into a freshly produced
+// signature (recovery bit 0, since our transactions already carry the
+// spender's PubKey alongside the signature and don't rely on recovery).
+func signerV(signer Signer) *big.Int {
+	switch s := signer.(type) {
+	case ChainIDSigner:
+		return encodeChainIDV(s.ChainID, 0)
+	default:
+		return big.NewInt(0)
+	}
+}
+
+func hexID(txid []byte) string {
+	const hextable = "0123456789abcdef"
+	out := make([]byte, len(txid)*2)
+	for i, b := range txid {
+		out[i*2] = hextable[b>>4]
+		out[i*2+1] = hextable[b&0x0f]
+	}
+	return string(out)
+}
@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
+)
+
+// TestSighashDependsOnSpentOutput guards against the per-input digest
+// collapsing to the same value no matter which output is being spent: the
+// substituted PubKeyHash must actually reach the signed digest, not be
+// silently discarded by a second trim.
+func TestSighashDependsOnSpentOutput(t *testing.T) {
+	_, pub := newTestKey(t)
+
+	tx := &Transaction{
+		Input:  []TxInput{{Txid: []byte("prevtx"), Vout: 0, PubKey: pub}},
+		Output: []TxOutput{{Value: 10, PubKeyHash: wallet.HashPubKey(pub)}},
+	}
+	tx.ID = tx.Hash()
+
+	digestA := sighash(tx, 0, []byte("pubkeyhash-of-output-a"), LegacySigner{})
+	digestB := sighash(tx, 0, []byte("pubkeyhash-of-output-b"), LegacySigner{})
+
+	if bytes.Equal(digestA, digestB) {
+		t.Fatal("sighash digest does not depend on which output's PubKeyHash is substituted")
+	}
+}
+
+// TestSignVerifyRoundTrip is the integration-level companion to
+// TestSighashDependsOnSpentOutput: a transaction signed against its real
+// previous output must verify.
+func TestSignVerifyRoundTrip(t *testing.T) {
+	priv, pub := newTestKey(t)
+	pkHash := wallet.HashPubKey(pub)
+
+	prevTx := Transaction{ID: []byte("prevtx")}
+	prevTx.Output = []TxOutput{{Value: 10, PubKeyHash: pkHash}}
+	prevTXs := map[string]Transaction{hexID(prevTx.ID): prevTx}
+
+	tx := &Transaction{
+		Input:  []TxInput{{Txid: prevTx.ID, Vout: 0, PubKey: pub}},
+		Output: []TxOutput{{Value: 10, PubKeyHash: pkHash}},
+	}
+	tx.ID = tx.Hash()
+	tx.Sign(priv, prevTXs, LegacySigner{})
+
+	if !tx.Verify(prevTXs) {
+		t.Fatal("Verify rejected a correctly-signed transaction")
+	}
+}
@@ -2,189 +2,317 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/gob"
+	"fmt"
 	"log"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
 )
 
+// subsidy is the number of tokens awarded to whoever mines/proposes a block,
+// paid out via the block's coinbase transaction.
+const subsidy = 10
+
 // ============================================================================
 // TRANSACTION STRUCTURES
 // ============================================================================
 
-// Transaction represents a blockchain transaction that transfers value from
-// one party to another. Each transaction has:
-// - A unique ID (hash of transaction data)
-// - Inputs (proof of funds from sender)
-// - Outputs (destination and amount for recipient)
+// Transaction represents a transfer of value modeled as a set of spent and
+// created unspent transaction outputs (UTXOs), following the Bitcoin-style
+// UTXO model rather than an account/balance model.
 type Transaction struct {
-	ID     []byte     // Unique identifier (SHA-256 hash of transaction data)
-	Input  []TxInput  // List of transaction inputs (sender information)
-	Output []TxOutput // List of transaction outputs (recipient information)
+	ID     []byte     // Unique identifier (SHA-256 hash of the transaction data)
+	Input  []TxInput  // Outputs being spent, one per referenced previous output
+	Output []TxOutput // New outputs created by this transaction
 }
 
-// TxInput represents the input side of a transaction.
-// It proves that the sender has the authority to spend funds by providing:
-// - A digital signature (proves ownership of the private key)
-// - The sender's public key (identifies who is sending)
+// TxInput references a single output of an earlier transaction that is being
+// spent, along with the proof needed to spend it.
 type TxInput struct {
-	Signature []byte // ECDSA signature proving sender owns the private key
-	PublicKey []byte // Sender's public key (identifies the sender)
+	Txid      []byte // ID of the transaction containing the output being spent
+	Vout      int    // Index of that output within the referenced transaction
+	Signature []byte // ECDSA signature authorizing the spend
+	PubKey    []byte // Raw public key of the spender (hashed and compared against the output's PubKeyHash)
 }
 
-// TxOutput represents the output side of a transaction.
-// It specifies:
-// - How much value is being transferred
-// - Who the recipient is (identified by their public key)
+// TxOutput represents a new, unspent unit of value created by a transaction.
+// It is locked to whoever can prove ownership of the private key matching
+// PubKeyHash.
 type TxOutput struct {
-	Value     int    // Amount of tokens/coins being transferred
-	PublicKey []byte // Recipient's public key (who receives the funds)
+	Value      int    // Amount of tokens/coins held by this output
+	PubKeyHash []byte // RIPEMD160(SHA256(pubkey)) of the owner allowed to spend this output
+}
+
+// Lock locks the output to whoever holds the private key behind address, a
+// Base58Check-encoded wallet address. It fails if address is malformed.
+func (out *TxOutput) Lock(address []byte) error {
+	pubKeyHash, err := wallet.PubKeyHashFromAddress(address)
+	if err != nil {
+		return err
+	}
+	out.PubKeyHash = pubKeyHash
+	return nil
+}
+
+// IsLockedWithKey reports whether this output can be unlocked by the holder
+// of the private key matching pubKeyHash.
+func (out *TxOutput) IsLockedWithKey(pubKeyHash []byte) bool {
+	return bytes.Equal(out.PubKeyHash, pubKeyHash)
+}
+
+// UsesKey reports whether this input was signed by the holder of the private
+// key matching pubKeyHash.
+func (in *TxInput) UsesKey(pubKeyHash []byte) bool {
+	return bytes.Equal(HashPubKey(in.PubKey), pubKeyHash)
+}
+
+// NewTXOutput creates a new output of the given value, locked to address.
+// It fails if address is malformed.
+func NewTXOutput(value int, address []byte) (*TxOutput, error) {
+	txo := &TxOutput{Value: value}
+	if err := txo.Lock(address); err != nil {
+		return nil, err
+	}
+	return txo, nil
+}
+
+// HashPubKey hashes a raw public key with RIPEMD160(SHA256(pubkey)), the
+// digest used to lock and unlock transaction outputs. It delegates to the
+// wallet package, which derives addresses with the same digest.
+func HashPubKey(pubKey []byte) []byte {
+	return wallet.HashPubKey(pubKey)
 }
 
 // ============================================================================
-// TRANSACTION CREATION
+// COINBASE TRANSACTIONS
 // ============================================================================
 
-// NewTransaction creates a new transaction and signs it using ECDSA.
-// 
-// Process:
-// 1. Create transaction input (sender info) and output (recipient + amount)
-// 2. Generate a unique transaction ID by hashing the transaction data
-// 3. Sign the transaction ID with the sender's private key
-// 4. Attach the signature to the input to prove authenticity
-//
-// Parameters:
-//   privateKey: sender's ECDSA private key (used to sign the transaction)
-//   recipient:  recipient's public key (who receives the funds)
-//   amount:     number of tokens/coins to transfer
-//
-// Returns:
-//   *Transaction: pointer to the newly created and signed transaction
-func NewTransaction(privateKey ecdsa.PrivateKey, recipient []byte, amount int) *Transaction {
-	// Create the transaction input (sender side)
-	// Initially empty signature - will be filled after signing
-	txIn := TxInput{
-		PublicKey: privateKey.PublicKey.X.Bytes(), // Sender's public key
+// NewCoinbaseTx creates a coinbase transaction, the first transaction in a
+// block, which awards the block subsidy to address "to" and carries no real
+// input (it spends nothing). data is arbitrary and defaults to random bytes
+// when empty, matching Bitcoin's coinbase scriptSig convention. It fails if
+// to is a malformed address.
+func NewCoinbaseTx(to []byte, data string) (*Transaction, error) {
+	if data == "" {
+		randData := make([]byte, 20)
+		if _, err := rand.Read(randData); err != nil {
+			log.Panic(err)
+		}
+		data = fmt.Sprintf("%x", randData)
 	}
 
-	// Create the transaction output (recipient side)
-	txOut := TxOutput{
-		Value:     amount,    // Amount to transfer
-		PublicKey: recipient, // Recipient's public key
+	txin := TxInput{Txid: []byte{}, Vout: -1, Signature: nil, PubKey: []byte(data)}
+	txout, err := NewTXOutput(subsidy, to)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build the transaction structure
-	tx := Transaction{
-		Input:  []TxInput{txIn},
-		Output: []TxOutput{txOut},
-	}
+	tx := Transaction{Input: []TxInput{txin}, Output: []TxOutput{*txout}}
+	tx.ID = tx.Hash()
 
-	// Generate a unique ID for this transaction by hashing its contents
-	tx.ID = tx.hashTransaction()
+	return &tx, nil
+}
 
-	// Sign the transaction ID with the sender's private key
-	// This proves that the sender authorized this transaction
-	// ECDSA signature returns two big integers (r, s)
-	r, s, err := ecdsa.Sign(rand.Reader, &privateKey, tx.ID)
-	if err != nil {
-		log.Panic(err)
-	}
+// IsCoinbase reports whether the transaction is a coinbase transaction, i.e.
+// it has exactly one input that references no previous output.
+func (tx *Transaction) IsCoinbase() bool {
+	return len(tx.Input) == 1 && len(tx.Input[0].Txid) == 0 && tx.Input[0].Vout == -1
+}
+
+// ============================================================================
+// SIGNING AND VERIFICATION
+// ============================================================================
 
-	// Combine r and s into a single signature byte array
-	signature := append(r.Bytes(), s.Bytes()...)
-	
-	// Attach the signature to the input to prove authenticity
-	tx.Input[0].Signature = signature
+// TrimmedCopy returns a copy of the transaction with every input's Signature
+// and PubKey cleared. It is the basis of the per-input digest a Signer
+// hashes, signs and verifies; see signer.go.
+func (tx *Transaction) TrimmedCopy() Transaction {
+	var inputs []TxInput
+	var outputs []TxOutput
 
-	return &tx
+	for _, vin := range tx.Input {
+		inputs = append(inputs, TxInput{Txid: vin.Txid, Vout: vin.Vout, Signature: nil, PubKey: nil})
+	}
+	for _, vout := range tx.Output {
+		outputs = append(outputs, TxOutput{Value: vout.Value, PubKeyHash: vout.PubKeyHash})
+	}
+
+	return Transaction{ID: tx.ID, Input: inputs, Output: outputs}
 }
 
 // ============================================================================
-// TRANSACTION HASHING
+// HASHING
 // ============================================================================
 
-// hashTransaction generates a unique identifier for the transaction by hashing
-// its core data (sender's public key, recipient's public key, and amount).
-// 
-// Why hash?
-// - Creates a unique, fixed-size identifier for the transaction
-// - Any change to transaction data will change the hash
-// - Used as the data that gets signed (proves transaction integrity)
-//
-// Returns:
-//   []byte: SHA-256 hash of the transaction data
-func (tx *Transaction) hashTransaction() []byte {
-	// Combine all transaction data into a single byte array
-	// Using: sender's public key + recipient's public key + amount
-	combinedData := bytes.Join([][]byte{
-		tx.Input[0].PublicKey,                    // Who is sending
-		tx.Output[0].PublicKey,                   // Who is receiving
-		[]byte(string(rune(tx.Output[0].Value))), // How much is being sent
-	}, []byte{})
-
-	// Hash the combined data using SHA-256
-	hash := sha256.Sum256(combinedData)
-	
-	// Return as a byte slice (convert from fixed-size array)
+// Hash returns the SHA-256 digest of the transaction with its ID field
+// cleared, used as the transaction's ID. Sign and Verify do not use it; they
+// operate on the per-input digest built by sighash (see signer.go).
+func (tx *Transaction) Hash() []byte {
+	txCopy := *tx
+	txCopy.ID = []byte{}
+
+	hash := sha256.Sum256(txCopy.Serialize())
 	return hash[:]
 }
 
 // ============================================================================
 // SERIALIZATION (for storage and network transmission)
 // ============================================================================
+//
+// Serialize always writes the current canonical format (versionLengthPrefixed).
+// SerializeV1 and SerializeV2 are exposed directly so callers that need a
+// specific wire version (e.g. tests, or a node migrating old data) can ask
+// for it explicitly. Every payload starts with a uint16 version prefix;
+// DeserializeTransaction reads that prefix and dispatches to the matching
+// decoder instead of assuming a single fixed layout, so adding a field in a
+// future version doesn't invalidate transactions already on disk.
 
 // Serialize converts the transaction into a byte array for storage or
-// transmission over the network.
-//
-// Why serialize?
-// - Transactions need to be stored in the blockchain
-// - Transactions need to be sent over the network to other nodes
-// - Byte arrays are the universal format for data storage/transmission
-//
-// Uses Go's gob encoding (efficient binary format)
-//
-// Returns:
-//   []byte: serialized transaction data
+// transmission over the network, using the current canonical format.
 func (tx *Transaction) Serialize() []byte {
-	var encoded bytes.Buffer
-	
-	// Create a gob encoder that writes to our buffer
-	enc := gob.NewEncoder(&encoded)
-	
-	// Encode the transaction into the buffer
-	err := enc.Encode(tx)
-	if err != nil {
+	return tx.SerializeV2()
+}
+
+// SerializeV1 encodes the transaction with encoding/gob, prefixed with its
+// version tag. Kept for reading/writing data from before the
+// length-prefixed format existed; new code should prefer SerializeV2.
+func (tx *Transaction) SerializeV1() []byte {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(tx); err != nil {
 		log.Panic(err)
 	}
-	
-	// Return the encoded bytes
-	return encoded.Bytes()
+
+	var out bytes.Buffer
+	putUint16(&out, versionGob)
+	out.Write(payload.Bytes())
+	return out.Bytes()
 }
 
-// DeserializeTransaction converts a byte array back into a Transaction object.
-//
-// Why deserialize?
-// - Retrieve transactions from storage
-// - Receive transactions from other nodes over the network
-// - Reconstruct the original transaction structure from bytes
-//
-// Parameters:
-//   data: serialized transaction bytes (from Serialize())
-//
-// Returns:
-//   *Transaction: reconstructed transaction object
-func DeserializeTransaction(data []byte) *Transaction {
-	var transaction Transaction
-	
-	// Create a gob decoder that reads from the byte data
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	
-	// Decode the bytes back into a Transaction struct
-	err := decoder.Decode(&transaction)
+// SerializeV2 encodes the transaction as a uint16 version prefix followed by
+// every field length-tagged in a fixed order, so truncated or malformed
+// input is caught by DeserializeTransaction as an error rather than a
+// gob panic.
+func (tx *Transaction) SerializeV2() []byte {
+	var buf bytes.Buffer
+	putUint16(&buf, versionLengthPrefixed)
+
+	putBytes(&buf, tx.ID)
+
+	putUint64(&buf, uint64(len(tx.Input)))
+	for _, vin := range tx.Input {
+		putBytes(&buf, vin.Txid)
+		putInt64(&buf, int64(vin.Vout))
+		putBytes(&buf, vin.Signature)
+		putBytes(&buf, vin.PubKey)
+	}
+
+	putUint64(&buf, uint64(len(tx.Output)))
+	for _, vout := range tx.Output {
+		putInt64(&buf, int64(vout.Value))
+		putBytes(&buf, vout.PubKeyHash)
+	}
+
+	return buf.Bytes()
+}
+
+// DeserializeTransaction decodes a byte array produced by Serialize back
+// into a Transaction, dispatching on its version prefix. It returns an error
+// rather than panicking so a caller that received a malformed or corrupted
+// transaction from a peer can quarantine it instead of crashing the node.
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	r := bytes.NewReader(data)
+
+	version, err := takeUint16(r)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
+	}
+
+	var tx Transaction
+	switch version {
+	case versionGob:
+		if err := gob.NewDecoder(r).Decode(&tx); err != nil {
+			return nil, err
+		}
+	case versionLengthPrefixed:
+		if err := decodeTransactionV2(r, &tx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownVersion
+	}
+
+	// Reject a signed input whose v we don't recognize here rather than
+	// letting it silently misverify later.
+	if !tx.IsCoinbase() {
+		for _, vin := range tx.Input {
+			if _, _, v, err := parseSignature(vin.Signature); err == nil {
+				if _, _, _, err := deriveSigner(v).SignatureValues(vin.Signature); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &tx, nil
+}
+
+func decodeTransactionV2(r *bytes.Reader, tx *Transaction) error {
+	id, err := takeBytes(r)
+	if err != nil {
+		return err
+	}
+	tx.ID = id
+
+	// Each input is at minimum two empty length-prefixed blobs plus an
+	// int64 vout and a third empty length-prefixed blob (20 bytes), so
+	// bound inputCount against that before trusting it to size an
+	// allocation.
+	inputCount, err := takeCount(r, 20)
+	if err != nil {
+		return err
+	}
+	tx.Input = make([]TxInput, inputCount)
+	for i := range tx.Input {
+		txid, err := takeBytes(r)
+		if err != nil {
+			return err
+		}
+		vout, err := takeInt64(r)
+		if err != nil {
+			return err
+		}
+		signature, err := takeBytes(r)
+		if err != nil {
+			return err
+		}
+		pubKey, err := takeBytes(r)
+		if err != nil {
+			return err
+		}
+		tx.Input[i] = TxInput{Txid: txid, Vout: int(vout), Signature: signature, PubKey: pubKey}
 	}
-	
-	return &transaction
-}
\ No newline at end of file
+
+	// Each output is at minimum an int64 value plus an empty
+	// length-prefixed blob (12 bytes), so bound outputCount against that
+	// before trusting it to size an allocation.
+	outputCount, err := takeCount(r, 12)
+	if err != nil {
+		return err
+	}
+	tx.Output = make([]TxOutput, outputCount)
+	for i := range tx.Output {
+		value, err := takeInt64(r)
+		if err != nil {
+			return err
+		}
+		pubKeyHash, err := takeBytes(r)
+		if err != nil {
+			return err
+		}
+		tx.Output[i] = TxOutput{Value: int(value), PubKeyHash: pubKeyHash}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"testing"
+
+	"github.com/TheZuckaNator/go-blockchain/wallet"
+)
+
+func newTestKey(t *testing.T) (ecdsa.PrivateKey, []byte) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(ellipticCurve(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pub := append(priv.PublicKey.X.Bytes(), priv.PublicKey.Y.Bytes()...)
+	return *priv, pub
+}
+
+// TestVerifyRejectsMismatchedKey guards against spending an output locked to
+// someone else's key: referencing their Txid/Vout and self-signing with an
+// unrelated keypair must not verify, even though ecdsa.Verify alone would be
+// satisfied against the attacker's own embedded PubKey.
+func TestVerifyRejectsMismatchedKey(t *testing.T) {
+	ownerPriv, ownerPub := newTestKey(t)
+	attackerPriv, attackerPub := newTestKey(t)
+
+	prevTx := Transaction{ID: []byte("prevtx")}
+	prevTx.Output = []TxOutput{{Value: 10, PubKeyHash: wallet.HashPubKey(ownerPub)}}
+	prevTXs := map[string]Transaction{hexID(prevTx.ID): prevTx}
+
+	theft := &Transaction{
+		Input:  []TxInput{{Txid: prevTx.ID, Vout: 0, PubKey: attackerPub}},
+		Output: []TxOutput{{Value: 10, PubKeyHash: wallet.HashPubKey(attackerPub)}},
+	}
+	theft.ID = theft.Hash()
+	theft.Sign(attackerPriv, prevTXs, LegacySigner{})
+
+	if theft.Verify(prevTXs) {
+		t.Fatal("Verify accepted a spend signed with a key that doesn't match the referenced output's PubKeyHash")
+	}
+
+	legit := &Transaction{
+		Input:  []TxInput{{Txid: prevTx.ID, Vout: 0, PubKey: ownerPub}},
+		Output: []TxOutput{{Value: 10, PubKeyHash: wallet.HashPubKey(attackerPub)}},
+	}
+	legit.ID = legit.Hash()
+	legit.Sign(ownerPriv, prevTXs, LegacySigner{})
+
+	if !legit.Verify(prevTXs) {
+		t.Fatal("Verify rejected a correctly-signed spend from the output's real owner")
+	}
+}
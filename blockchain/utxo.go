@@ -0,0 +1,244 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// utxoBucket is the BoltDB bucket used to persist the UTXO index, commonly
+// called "chainstate" after Bitcoin Core's equivalent database.
+const utxoBucket = "chainstate"
+
+// UTXOSet is a persistent index of every unspent transaction output, kept in
+// sync with the chain via Update so balance queries and input selection
+// don't need to rescan every block.
+type UTXOSet struct {
+	Blockchain *Blockchain
+}
+
+// txOutputs wraps a slice of TxOutput purely so gob has a single value to
+// encode per chainstate entry.
+type txOutputs struct {
+	Outputs []TxOutput
+}
+
+// Reindex rebuilds the chainstate bucket from scratch by rescanning the
+// whole chain. It's the slow path, meant to be run once (e.g. right after
+// InitBlockchain) rather than after every block.
+func (u UTXOSet) Reindex() {
+	db := u.Blockchain.DB
+	bucketName := []byte(utxoBucket)
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(bucketName)
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		_, err = tx.CreateBucket(bucketName)
+		return err
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	utxo := u.Blockchain.FindUTXO()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+
+		for txID, outs := range utxo {
+			key, err := hex.DecodeString(txID)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, serializeOutputs(outs)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// Update applies a single newly-added block to the UTXO set: every output an
+// input of the block spends is removed (or, if other outputs of that
+// transaction remain unspent, the entry is shrunk), and every new output the
+// block creates is added.
+func (u UTXOSet) Update(block *Block) {
+	db := u.Blockchain.DB
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+
+		for _, transaction := range block.Transactions {
+			if !transaction.IsCoinbase() {
+				for _, vin := range transaction.Input {
+					outsBytes := b.Get(vin.Txid)
+					if outsBytes == nil {
+						// Already removed from chainstate by an earlier
+						// spend (or never there); nothing left to do for
+						// this input.
+						continue
+					}
+					outs := deserializeOutputs(outsBytes)
+
+					var remaining []TxOutput
+					for outIdx, out := range outs.Outputs {
+						if outIdx != vin.Vout {
+							remaining = append(remaining, out)
+						}
+					}
+
+					if len(remaining) == 0 {
+						if err := b.Delete(vin.Txid); err != nil {
+							return err
+						}
+					} else if err := b.Put(vin.Txid, serializeOutputs(remaining)); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := b.Put(transaction.ID, serializeOutputs(transaction.Output)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// IsUnspent reports whether the output at index vout of transaction txID is
+// still present in the chainstate bucket. AddBlock calls this per input
+// before accepting a block, so a transaction can't spend an output a
+// previous block already spent even though FindTransaction would still find
+// the original transaction earlier in the chain's history.
+func (u UTXOSet) IsUnspent(txID []byte, vout int) bool {
+	db := u.Blockchain.DB
+	unspent := false
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		outsBytes := b.Get(txID)
+		if outsBytes == nil {
+			return nil
+		}
+		outs := deserializeOutputs(outsBytes)
+		unspent = vout >= 0 && vout < len(outs.Outputs)
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return unspent
+}
+
+// FindSpendableOutputs finds enough unspent outputs locked to pubKeyHash to
+// cover amount, returning the total value they carry and, per transaction ID
+// (hex-encoded), the indices of the outputs to spend.
+func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[string][]int) {
+	unspentOutputs := make(map[string][]int)
+	accumulated := 0
+	db := u.Blockchain.DB
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		c := b.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			outs := deserializeOutputs(v)
+
+			for outIdx, out := range outs.Outputs {
+				if accumulated >= amount {
+					break
+				}
+				if out.IsLockedWithKey(pubKeyHash) {
+					accumulated += out.Value
+					txID := hex.EncodeToString(k)
+					unspentOutputs[txID] = append(unspentOutputs[txID], outIdx)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return accumulated, unspentOutputs
+}
+
+// FindUTXO returns every unspent output locked to pubKeyHash, e.g. to
+// compute an address's balance.
+func (u UTXOSet) FindUTXO(pubKeyHash []byte) []TxOutput {
+	var UTXOs []TxOutput
+	db := u.Blockchain.DB
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		c := b.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			outs := deserializeOutputs(v)
+			for _, out := range outs.Outputs {
+				if out.IsLockedWithKey(pubKeyHash) {
+					UTXOs = append(UTXOs, out)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return UTXOs
+}
+
+// CountTransactions returns the number of transactions with at least one
+// unspent output, i.e. the number of entries in the chainstate bucket.
+func (u UTXOSet) CountTransactions() int {
+	db := u.Blockchain.DB
+	counter := 0
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		c := b.Cursor()
+
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			counter++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return counter
+}
+
+func serializeOutputs(outs []TxOutput) []byte {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(txOutputs{Outputs: outs}); err != nil {
+		log.Panic(err)
+	}
+	return buf.Bytes()
+}
+
+func deserializeOutputs(data []byte) txOutputs {
+	var outs txOutputs
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&outs); err != nil {
+		log.Panic(err)
+	}
+	return outs
+}
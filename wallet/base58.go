@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"bytes"
+	"math/big"
+)
+
+// base58Alphabet is the Bitcoin Base58 alphabet: the digit 0, and the
+// letters O, I and l are omitted to avoid visual confusion between
+// characters when addresses are read by a human.
+var base58Alphabet = []byte("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+
+// Base58Encode encodes input using Base58, as used for wallet addresses.
+func Base58Encode(input []byte) []byte {
+	var result []byte
+
+	x := new(big.Int).SetBytes(input)
+	base := big.NewInt(int64(len(base58Alphabet)))
+	zero := big.NewInt(0)
+	mod := &big.Int{}
+
+	for x.Cmp(zero) != 0 {
+		x.DivMod(x, base, mod)
+		result = append(result, base58Alphabet[mod.Int64()])
+	}
+
+	reverse(result)
+
+	// Preserve leading zero bytes: each one encodes as a leading '1'.
+	for _, b := range input {
+		if b != 0x00 {
+			break
+		}
+		result = append([]byte{base58Alphabet[0]}, result...)
+	}
+
+	return result
+}
+
+// Base58Decode reverses Base58Encode.
+func Base58Decode(input []byte) []byte {
+	result := big.NewInt(0)
+	base := big.NewInt(int64(len(base58Alphabet)))
+
+	for _, b := range input {
+		charIndex := bytes.IndexByte(base58Alphabet, b)
+		result.Mul(result, base)
+		result.Add(result, big.NewInt(int64(charIndex)))
+	}
+
+	decoded := result.Bytes()
+	// Each leading '1' stands for one leading zero byte.
+	for _, b := range input {
+		if b != base58Alphabet[0] {
+			break
+		}
+		decoded = append([]byte{0x00}, decoded...)
+	}
+
+	return decoded
+}
+
+func reverse(data []byte) {
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+}
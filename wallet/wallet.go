@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// checksumLength is the number of bytes of the double-SHA-256 checksum
+	// appended to an address before Base58 encoding.
+	checksumLength = 4
+	// addressVersion is the version byte prefixed to every address produced
+	// by this wallet.
+	addressVersion = byte(0x00)
+)
+
+// Wallet holds an ECDSA keypair that an address can be derived from and
+// transactions signed with.
+type Wallet struct {
+	PrivateKey ecdsa.PrivateKey
+	PublicKey  []byte
+}
+
+// NewWallet generates a fresh ECDSA keypair and wraps it in a Wallet.
+func NewWallet() *Wallet {
+	privateKey, publicKey := newKeyPair()
+	return &Wallet{PrivateKey: privateKey, PublicKey: publicKey}
+}
+
+func newKeyPair() (ecdsa.PrivateKey, []byte) {
+	curve := elliptic.P256()
+
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	publicKey := append(privateKey.PublicKey.X.Bytes(), privateKey.PublicKey.Y.Bytes()...)
+	return *privateKey, publicKey
+}
+
+// GetAddress derives this wallet's human-readable, Base58Check-encoded
+// address: version || RIPEMD160(SHA256(pubkey)), followed by a 4-byte
+// double-SHA-256 checksum.
+func (w Wallet) GetAddress() []byte {
+	pubKeyHash := HashPubKey(w.PublicKey)
+
+	versionedPayload := append([]byte{addressVersion}, pubKeyHash...)
+	checksum := checksum(versionedPayload)
+
+	fullPayload := append(versionedPayload, checksum...)
+	return Base58Encode(fullPayload)
+}
+
+// HashPubKey hashes a raw public key with RIPEMD160(SHA256(pubkey)), the
+// digest embedded in every address.
+func HashPubKey(pubKey []byte) []byte {
+	sha := sha256.Sum256(pubKey)
+
+	hasher := ripemd160.New()
+	if _, err := hasher.Write(sha[:]); err != nil {
+		log.Panic(err)
+	}
+
+	return hasher.Sum(nil)
+}
+
+// checksum computes the 4-byte double-SHA-256 checksum of payload.
+func checksum(payload []byte) []byte {
+	firstHash := sha256.Sum256(payload)
+	secondHash := sha256.Sum256(firstHash[:])
+	return secondHash[:checksumLength]
+}
+
+// ValidateAddress reverses a Base58Check-encoded address and reports
+// whether its checksum is valid.
+func ValidateAddress(address []byte) bool {
+	decoded := Base58Decode(address)
+	if len(decoded) < checksumLength+1 {
+		return false
+	}
+
+	actualChecksum := decoded[len(decoded)-checksumLength:]
+	version := decoded[0]
+	pubKeyHash := decoded[1 : len(decoded)-checksumLength]
+
+	targetChecksum := checksum(append([]byte{version}, pubKeyHash...))
+
+	return bytes.Equal(actualChecksum, targetChecksum)
+}
+
+// PubKeyHashFromAddress decodes a Base58Check-encoded address and returns
+// the public key hash embedded in it, stripping the version byte and
+// checksum. It returns an error instead of panicking if address is too
+// short or fails its checksum, the same validity check ValidateAddress
+// performs.
+func PubKeyHashFromAddress(address []byte) ([]byte, error) {
+	if !ValidateAddress(address) {
+		return nil, fmt.Errorf("wallet: invalid address %q", address)
+	}
+
+	decoded := Base58Decode(address)
+	return decoded[1 : len(decoded)-checksumLength], nil
+}
@@ -0,0 +1,36 @@
+package wallet
+
+import "testing"
+
+// TestPubKeyHashFromAddressRejectsMalformedAddress guards against the
+// panic a too-short or checksum-invalid address used to trigger by slicing
+// past the decoded payload without first checking its length.
+func TestPubKeyHashFromAddressRejectsMalformedAddress(t *testing.T) {
+	for _, address := range [][]byte{
+		nil,
+		[]byte(""),
+		[]byte("x"),
+		[]byte("not a real address"),
+	} {
+		if _, err := PubKeyHashFromAddress(address); err == nil {
+			t.Errorf("PubKeyHashFromAddress(%q) succeeded on a malformed address", address)
+		}
+	}
+}
+
+// TestPubKeyHashFromAddressRoundTrip checks a real wallet's own address
+// decodes back to the same public key hash it was derived from.
+func TestPubKeyHashFromAddressRoundTrip(t *testing.T) {
+	w := NewWallet()
+	address := w.GetAddress()
+
+	pubKeyHash, err := PubKeyHashFromAddress(address)
+	if err != nil {
+		t.Fatalf("PubKeyHashFromAddress rejected a valid address: %v", err)
+	}
+
+	want := HashPubKey(w.PublicKey)
+	if string(pubKeyHash) != string(want) {
+		t.Fatalf("PubKeyHashFromAddress = %x, want %x", pubKeyHash, want)
+	}
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/gob"
+	"log"
+	"os"
+	"sort"
+)
+
+// walletFile is the default on-disk location of a gob-serialized Wallets
+// store.
+const walletFile = "wallet.dat"
+
+// Wallets is a persistent store of wallets, keyed by address.
+type Wallets struct {
+	Wallets map[string]*Wallet
+}
+
+// NewWallets loads the Wallets store from walletFile, or returns an empty
+// store if the file does not yet exist.
+func NewWallets() (*Wallets, error) {
+	wallets := &Wallets{Wallets: make(map[string]*Wallet)}
+
+	err := wallets.LoadFile()
+	if os.IsNotExist(err) {
+		return wallets, nil
+	}
+	return wallets, err
+}
+
+// AddWallet generates a new wallet, stores it under its address and returns
+// that address.
+func (ws *Wallets) AddWallet() []byte {
+	wallet := NewWallet()
+	address := wallet.GetAddress()
+	ws.Wallets[string(address)] = wallet
+	return address
+}
+
+// GetWallet returns the wallet stored under address.
+func (ws *Wallets) GetWallet(address []byte) (*Wallet, bool) {
+	w, ok := ws.Wallets[string(address)]
+	return w, ok
+}
+
+// GetAddresses returns every address currently in the store, sorted for
+// stable output.
+func (ws *Wallets) GetAddresses() []string {
+	var addresses []string
+	for address := range ws.Wallets {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// LoadFile reads and decodes the Wallets store from walletFile.
+func (ws *Wallets) LoadFile() error {
+	fileContent, err := os.ReadFile(walletFile)
+	if err != nil {
+		return err
+	}
+
+	gob.Register(elliptic.P256())
+
+	var decoded Wallets
+	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
+	ws.Wallets = decoded.Wallets
+	return nil
+}
+
+// SaveFile encodes and writes the Wallets store to walletFile.
+func (ws *Wallets) SaveFile() {
+	var content bytes.Buffer
+
+	gob.Register(elliptic.P256())
+
+	encoder := gob.NewEncoder(&content)
+	if err := encoder.Encode(ws); err != nil {
+		log.Panic(err)
+	}
+
+	if err := os.WriteFile(walletFile, content.Bytes(), 0600); err != nil {
+		log.Panic(err)
+	}
+}